@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/andikanugr/newrelic-condition-collector/nrclient"
+)
+
+// runDiff implements the "diff" subcommand: it fetches the current NRQL
+// conditions for a policy and compares them against a checked-in baseline
+// snapshot, so drift introduced outside of IaC (e.g. an edit in the UI) can
+// be caught in CI.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "path to the config file")
+	baselinePath := fs.String("baseline", "conditions.json", "path to the baseline JSON snapshot")
+	policyID := fs.String("policy", "", "policy ID to diff against the baseline")
+	accountID := fs.Int("account", 0, "account ID to query (defaults to the first account in the config)")
+	writeBaselineFlag := fs.Bool("write-baseline", false, "write the current snapshot as the new baseline instead of diffing")
+	timeout := fs.Duration("timeout", 30*time.Second, "HTTP timeout per NerdGraph request")
+	fs.Parse(args)
+
+	if *policyID == "" {
+		fmt.Println("Error: --policy is required")
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error loading configuration:", err)
+		os.Exit(2)
+	}
+
+	account, ok := findAccount(config, *accountID)
+	if !ok {
+		fmt.Println("Error: no matching account found in config")
+		os.Exit(2)
+	}
+
+	client := nrclient.NewGraphQLClient(account.APIKey, account.Region, *timeout)
+	current, err := client.FetchNRQLConditions(context.Background(), account.AccountID, *policyID)
+	if err != nil {
+		fmt.Println("Error fetching current conditions:", err)
+		os.Exit(2)
+	}
+
+	if *writeBaselineFlag {
+		if err := writeBaseline(*baselinePath, account.AccountID, *policyID, current); err != nil {
+			fmt.Println("Error writing baseline:", err)
+			os.Exit(2)
+		}
+		fmt.Printf("Baseline written to %s\n", *baselinePath)
+		return
+	}
+
+	baseline, err := loadBaseline(*baselinePath)
+	if err != nil {
+		fmt.Println("Error loading baseline:", err)
+		os.Exit(2)
+	}
+	baseline = filterConditions(baseline, account.AccountID, *policyID)
+
+	drift := diffConditions(baseline, current)
+	if len(drift) == 0 {
+		fmt.Println("No drift detected")
+		return
+	}
+
+	for _, line := range drift {
+		fmt.Println(line)
+	}
+	os.Exit(1)
+}
+
+// findAccount returns the AccountConfig matching accountID, or the first
+// configured account if accountID is zero.
+func findAccount(config *Config, accountID int) (AccountConfig, bool) {
+	if accountID == 0 && len(config.Accounts) > 0 {
+		return config.Accounts[0], true
+	}
+	for _, account := range config.Accounts {
+		if account.AccountID == accountID {
+			return account, true
+		}
+	}
+	return AccountConfig{}, false
+}
+
+// loadBaseline loads a baseline snapshot previously written by
+// writeBaseline/SaveNRQLConditionsAsJSON. A baseline file can hold
+// conditions for many accounts/policies, so callers must filter the result
+// with filterConditions before diffing.
+func loadBaseline(filename string) ([]nrclient.NRQLCondition, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var conditions []nrclient.NRQLCondition
+	if err := json.NewDecoder(file).Decode(&conditions); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+// filterConditions returns only the conditions belonging to accountID and
+// policyID, so a baseline file shared across many policies doesn't report
+// every other policy's conditions as drift.
+func filterConditions(conditions []nrclient.NRQLCondition, accountID int, policyID string) []nrclient.NRQLCondition {
+	var filtered []nrclient.NRQLCondition
+	for _, condition := range conditions {
+		if condition.AccountID == accountID && condition.PolicyID == policyID {
+			filtered = append(filtered, condition)
+		}
+	}
+	return filtered
+}
+
+// writeBaseline replaces accountID/policyID's entries in the baseline file
+// at path with current, preserving any other accounts'/policies' entries
+// already recorded there (or creating the file if it doesn't exist yet), so
+// one baseline file can track many policies without --write-baseline for
+// one policy clobbering the rest.
+func writeBaseline(path string, accountID int, policyID string, current []nrclient.NRQLCondition) error {
+	existing, err := loadBaseline(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var merged []nrclient.NRQLCondition
+	for _, condition := range existing {
+		if condition.AccountID == accountID && condition.PolicyID == policyID {
+			continue
+		}
+		merged = append(merged, condition)
+	}
+	merged = append(merged, current...)
+
+	return SaveNRQLConditionsAsJSON(path, merged)
+}
+
+// conditionDiff groups the unified-diff lines produced for a single
+// condition so they can be sorted together by name.
+type conditionDiff struct {
+	name  string
+	lines []string
+}
+
+// diffConditions compares a baseline snapshot against the current state and
+// returns a unified-diff-style line per added, removed, or changed
+// condition, sorted by condition name for stable output. Both slices must
+// already be scoped to a single account/policy (see filterConditions).
+func diffConditions(baseline, current []nrclient.NRQLCondition) []string {
+	baselineByName := make(map[string]nrclient.NRQLCondition, len(baseline))
+	for _, condition := range baseline {
+		baselineByName[condition.Name] = condition
+	}
+	currentByName := make(map[string]nrclient.NRQLCondition, len(current))
+	for _, condition := range current {
+		currentByName[condition.Name] = condition
+	}
+
+	var diffs []conditionDiff
+	for name := range currentByName {
+		if _, ok := baselineByName[name]; !ok {
+			diffs = append(diffs, conditionDiff{name: name, lines: []string{fmt.Sprintf("+ %s (added)", name)}})
+		}
+	}
+	for name, b := range baselineByName {
+		c, ok := currentByName[name]
+		if !ok {
+			diffs = append(diffs, conditionDiff{name: name, lines: []string{fmt.Sprintf("- %s (removed)", name)}})
+			continue
+		}
+		if fieldLines := diffConditionFields(b, c); len(fieldLines) > 0 {
+			lines := append([]string{fmt.Sprintf("~ %s (changed)", name)}, fieldLines...)
+			diffs = append(diffs, conditionDiff{name: name, lines: lines})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].name < diffs[j].name })
+
+	var out []string
+	for _, d := range diffs {
+		out = append(out, d.lines...)
+	}
+	return out
+}
+
+// diffConditionFields compares the mutable fields of a single condition
+// between baseline and current, returning one indented line per change.
+func diffConditionFields(b, c nrclient.NRQLCondition) []string {
+	var lines []string
+
+	if b.Enabled != c.Enabled {
+		lines = append(lines, fmt.Sprintf("  enabled: %t -> %t", b.Enabled, c.Enabled))
+	}
+	if b.Type != c.Type {
+		lines = append(lines, fmt.Sprintf("  type: %s -> %s", b.Type, c.Type))
+	}
+	if b.Nrql.Query != c.Nrql.Query {
+		lines = append(lines, fmt.Sprintf("  nrql.query: %q -> %q", b.Nrql.Query, c.Nrql.Query))
+	}
+	if b.RunbookURL != c.RunbookURL {
+		lines = append(lines, fmt.Sprintf("  runbookUrl: %q -> %q", b.RunbookURL, c.RunbookURL))
+	}
+	if b.Signal.AggregationWindow != c.Signal.AggregationWindow {
+		lines = append(lines, fmt.Sprintf("  signal.aggregationWindow: %d -> %d", b.Signal.AggregationWindow, c.Signal.AggregationWindow))
+	}
+	if b.Signal.EvaluationOffset != c.Signal.EvaluationOffset {
+		lines = append(lines, fmt.Sprintf("  signal.evaluationOffset: %d -> %d", b.Signal.EvaluationOffset, c.Signal.EvaluationOffset))
+	}
+
+	baselineTerms := make(map[string]nrclient.Term, len(b.Terms))
+	for _, term := range b.Terms {
+		baselineTerms[term.Priority] = term
+	}
+	currentTerms := make(map[string]nrclient.Term, len(c.Terms))
+	for _, term := range c.Terms {
+		currentTerms[term.Priority] = term
+	}
+
+	seen := make(map[string]bool)
+	var priorities []string
+	for _, term := range append(append([]nrclient.Term{}, b.Terms...), c.Terms...) {
+		if !seen[term.Priority] {
+			seen[term.Priority] = true
+			priorities = append(priorities, term.Priority)
+		}
+	}
+	sort.Strings(priorities)
+
+	for _, priority := range priorities {
+		bt, bok := baselineTerms[priority]
+		ct, cok := currentTerms[priority]
+		switch {
+		case !bok:
+			lines = append(lines, fmt.Sprintf("  term[%s]: added", priority))
+		case !cok:
+			lines = append(lines, fmt.Sprintf("  term[%s]: removed", priority))
+		default:
+			lines = append(lines, diffTermFields(priority, bt, ct)...)
+		}
+	}
+
+	return lines
+}
+
+// diffTermFields compares a single term between baseline and current,
+// returning one indented line per changed field.
+func diffTermFields(priority string, b, c nrclient.Term) []string {
+	var lines []string
+
+	if b.Operator != c.Operator {
+		lines = append(lines, fmt.Sprintf("  term[%s].operator: %s -> %s", priority, b.Operator, c.Operator))
+	}
+	if b.Threshold != c.Threshold {
+		lines = append(lines, fmt.Sprintf("  term[%s].threshold: %s -> %s",
+			priority,
+			strconv.FormatFloat(b.Threshold, 'f', -1, 64),
+			strconv.FormatFloat(c.Threshold, 'f', -1, 64)))
+	}
+	if b.ThresholdDuration != c.ThresholdDuration {
+		lines = append(lines, fmt.Sprintf("  term[%s].thresholdDuration: %d -> %d", priority, b.ThresholdDuration, c.ThresholdDuration))
+	}
+	if b.ThresholdOccurrences != c.ThresholdOccurrences {
+		lines = append(lines, fmt.Sprintf("  term[%s].thresholdOccurrences: %s -> %s", priority, b.ThresholdOccurrences, c.ThresholdOccurrences))
+	}
+
+	return lines
+}
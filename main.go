@@ -1,74 +1,111 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/andikanugr/newrelic-condition-collector/nrclient"
 )
 
-// Config struct to hold New Relic API key and alert policy ID
+// Config holds the New Relic accounts to query and the alert policies to
+// export NRQL conditions for within each one.
 type Config struct {
-	APIKey        string `json:"apiKey"`
-	AlertPolicyID string `json:"alertPolicyID"`
+	Accounts []AccountConfig `json:"accounts"`
 }
 
-// NRQLCondition struct to represent an NRQL alert condition
-type NRQLCondition struct {
-	Name    string `json:"name"`
-	Terms   []Term `json:"terms"`
-	Enabled bool   `json:"enabled"`
+// AccountConfig identifies a single New Relic account and the alert policy
+// IDs within it whose NRQL conditions should be exported.
+type AccountConfig struct {
+	AccountID int             `json:"accountID"`
+	APIKey    string          `json:"apiKey"`
+	Region    nrclient.Region `json:"region"`
+	PolicyIDs []string        `json:"policyIDs"`
 }
 
-// Term struct to represent a term within an NRQL alert condition
-type Term struct {
-	Duration     string `json:"duration"`
-	Operator     string `json:"operator"`
-	Threshold    string `json:"threshold"`
-	TimeFunction string `json:"time_function"`
-	Priority     string `json:"priority"`
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+	runExport(os.Args[1:])
 }
 
-func main() {
-	// Load configuration
-	config, err := loadConfig("config.json")
+// runExport is the default subcommand: it fetches NRQL conditions for every
+// configured account/policy and writes them out as CSV, Terraform, or JSON.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "path to the config file")
+	outputPath := fs.String("output", "", "path to write the export to (defaults to conditions.<format>)")
+	format := fs.String("format", "csv", "export format: csv, tf, or json")
+	timeout := fs.Duration("timeout", 30*time.Second, "HTTP timeout per NerdGraph request")
+	maxRetries := fs.Int("max-retries", nrclient.DefaultMaxRetries, "maximum retries for retryable (5xx/429) NerdGraph responses")
+	fs.Parse(args)
+
+	if *outputPath == "" {
+		*outputPath = fmt.Sprintf("conditions.%s", *format)
+	}
+
+	config, err := loadConfig(*configPath)
 	if err != nil {
 		fmt.Println("Error loading configuration:", err)
 		return
 	}
 
-	// Fetch NRQL alert conditions for the specified alert policy ID
-	nrqlConditions, err := fetchNRQLConditions(config.APIKey, config.AlertPolicyID)
-	if err != nil {
-		fmt.Printf("Error fetching NRQL alert conditions: %v\n", err)
-		return
+	ctx := context.Background()
+
+	var allConditions []nrclient.NRQLCondition
+	for _, account := range config.Accounts {
+		client := nrclient.NewGraphQLClient(account.APIKey, account.Region, *timeout)
+		client.MaxRetries = *maxRetries
+
+		for _, policyID := range account.PolicyIDs {
+			conditions, err := client.FetchNRQLConditions(ctx, account.AccountID, policyID)
+			if err != nil {
+				fmt.Printf("Error fetching NRQL alert conditions for account %d, policy %s: %v\n", account.AccountID, policyID, err)
+				return
+			}
+			allConditions = append(allConditions, conditions...)
+		}
 	}
 
 	// Print NRQL alert conditions
-	fmt.Printf("NRQL Alert Conditions for Policy ID %s:\n", config.AlertPolicyID)
-	for _, condition := range nrqlConditions {
-		fmt.Printf("Name: %s\n", condition.Name)
+	for _, condition := range allConditions {
+		fmt.Printf("Account: %d, Policy: %s, Name: %s\n", condition.AccountID, condition.PolicyID, condition.Name)
 		fmt.Println("Terms:")
 		for _, term := range condition.Terms {
-			fmt.Printf("  Duration: %s\n", term.Duration)
-			fmt.Printf("  Operator: %s\n", term.Operator)
-			fmt.Printf("  Threshold: %s\n", term.Threshold)
-			fmt.Printf("  Time Function: %s\n", term.TimeFunction)
 			fmt.Printf("  Priority: %s\n", term.Priority)
+			fmt.Printf("  Operator: %s\n", term.Operator)
+			fmt.Printf("  Threshold: %v\n", term.Threshold)
+			fmt.Printf("  Threshold Duration: %d\n", term.ThresholdDuration)
 		}
 		fmt.Println()
 	}
-	err = SaveNRQLConditionsAsCSV(fmt.Sprintf("%s.csv", config.AlertPolicyID), nrqlConditions)
+
+	switch *format {
+	case "csv":
+		err = SaveNRQLConditionsAsCSV(*outputPath, allConditions)
+	case "tf":
+		err = SaveNRQLConditionsAsTerraform(*outputPath, allConditions)
+	case "json":
+		err = SaveNRQLConditionsAsJSON(*outputPath, allConditions)
+	default:
+		fmt.Printf("Error: unknown format %q (want csv, tf, or json)\n", *format)
+		return
+	}
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
-	fmt.Println("CSV file saved successfully")
+	fmt.Printf("%s file saved successfully\n", *outputPath)
 }
 
-// LoadConfig loads configuration from a JSON file
+// loadConfig loads configuration from a JSON file
 func loadConfig(filename string) (*Config, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -86,126 +123,10 @@ func loadConfig(filename string) (*Config, error) {
 	return config, nil
 }
 
-// FetchNRQLConditions fetches NRQL alert conditions for a specific alert policy from New Relic
-func fetchNRQLConditions(apiKey string, policyID string) ([]NRQLCondition, error) {
-	url := fmt.Sprintf("https://api.newrelic.com/v2/alerts_nrql_conditions.json?policy_id=%s", policyID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Api-Key", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Decode response body
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
-	}
-
-	// Extract NRQL alert conditions
-	var nrqlConditions []NRQLCondition
-	conditions, ok := data["nrql_conditions"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("nrql_conditions field not found or has incorrect type")
-	}
-	for _, c := range conditions {
-		condition, ok := c.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("nrql condition has incorrect type")
-		}
-
-		name, ok := condition["name"].(string)
-		if !ok {
-			return nil, fmt.Errorf("name field is not a string")
-		}
-
-		enabled, ok := condition["enabled"].(bool)
-		if !ok {
-			return nil, fmt.Errorf("enabled field is not a boolean")
-		}
-
-		// Extracting terms
-		termsData, ok := condition["terms"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("terms field not found or has incorrect type")
-		}
-
-		// Iterate over terms
-		var terms []Term
-		for _, termData := range termsData {
-			term, ok := termData.(map[string]interface{})
-			if !ok {
-				return nil, fmt.Errorf("term has incorrect type")
-			}
-
-			// Extract duration field
-			duration, ok := term["duration"].(string)
-			if !ok {
-				return nil, fmt.Errorf("duration field is not a string")
-			}
-
-			operator, ok := term["operator"].(string)
-			if !ok {
-				return nil, fmt.Errorf("operator field is not a string")
-			}
-
-			// Extract and format threshold field
-			thresholdValue, ok := term["threshold"].(string)
-			if !ok {
-				return nil, fmt.Errorf("threshold field is not a string")
-			}
-			threshold, err := formatThreshold(thresholdValue)
-			if err != nil {
-				return nil, err
-			}
-
-			timeFunction, ok := term["time_function"].(string)
-			if !ok {
-				return nil, fmt.Errorf("time_function field is not a string")
-			}
-
-			priority, ok := term["priority"].(string)
-			if !ok {
-				return nil, fmt.Errorf("priority field is not a string")
-			}
-
-			terms = append(terms, Term{
-				Duration:     duration,
-				Operator:     operator,
-				Threshold:    threshold,
-				TimeFunction: timeFunction,
-				Priority:     priority,
-			})
-		}
-
-		nrqlConditions = append(nrqlConditions, NRQLCondition{
-			Name:    name,
-			Terms:   terms,
-			Enabled: enabled,
-		})
-	}
-
-	return nrqlConditions, nil
-}
-
-// formatThreshold formats the threshold value to its exact decimal representation
-func formatThreshold(value string) (string, error) {
-	f, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%.f", f), nil
-}
-
-// SaveNRQLConditionsAsCSV saves NRQL alert conditions to a CSV file
-func SaveNRQLConditionsAsCSV(filename string, nrqlConditions []NRQLCondition) error {
+// SaveNRQLConditionsAsCSV saves NRQL alert conditions to a CSV file, one row
+// per term so that conditions with multiple priorities (critical/warning)
+// are fully represented.
+func SaveNRQLConditionsAsCSV(filename string, conditions []nrclient.NRQLCondition) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -214,23 +135,32 @@ func SaveNRQLConditionsAsCSV(filename string, nrqlConditions []NRQLCondition) er
 
 	writer := csv.NewWriter(file)
 
-	// Write header
-	header := []string{"Condition Name", "Duration", "Operator", "Threshold", "Time Function", "Priority", "Active"}
+	header := []string{
+		"Account ID", "Policy ID", "Condition Name", "Type", "Enabled", "NRQL Query",
+		"Priority", "Operator", "Threshold", "Threshold Duration", "Threshold Occurrences",
+		"Aggregation Window", "Evaluation Offset", "Runbook URL",
+	}
 	if err := writer.Write(header); err != nil {
 		return err
 	}
 
-	// Write data
-	for _, condition := range nrqlConditions {
+	for _, condition := range conditions {
 		for _, term := range condition.Terms {
 			record := []string{
+				strconv.Itoa(condition.AccountID),
+				condition.PolicyID,
 				condition.Name,
-				term.Duration,
-				term.Operator,
-				term.Threshold,
-				term.TimeFunction,
-				term.Priority,
+				string(condition.Type),
 				fmt.Sprintf("%t", condition.Enabled),
+				condition.Nrql.Query,
+				term.Priority,
+				term.Operator,
+				strconv.FormatFloat(term.Threshold, 'f', -1, 64),
+				strconv.Itoa(term.ThresholdDuration),
+				term.ThresholdOccurrences,
+				strconv.Itoa(condition.Signal.AggregationWindow),
+				strconv.Itoa(condition.Signal.EvaluationOffset),
+				condition.RunbookURL,
 			}
 			if err := writer.Write(record); err != nil {
 				return err
@@ -238,7 +168,6 @@ func SaveNRQLConditionsAsCSV(filename string, nrqlConditions []NRQLCondition) er
 		}
 	}
 
-	// Flush writer
 	writer.Flush()
 
 	if err := writer.Error(); err != nil {
@@ -247,3 +176,17 @@ func SaveNRQLConditionsAsCSV(filename string, nrqlConditions []NRQLCondition) er
 
 	return nil
 }
+
+// SaveNRQLConditionsAsJSON saves NRQL alert conditions to a pretty-printed
+// JSON file.
+func SaveNRQLConditionsAsJSON(filename string, conditions []nrclient.NRQLCondition) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(conditions)
+}
@@ -0,0 +1,307 @@
+// Package nrclient implements a client for New Relic's NerdGraph GraphQL
+// API, used to fetch NRQL alert conditions.
+package nrclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Region selects which NerdGraph endpoint a GraphQLClient talks to.
+type Region string
+
+const (
+	RegionUS Region = "US"
+	RegionEU Region = "EU"
+)
+
+const (
+	usEndpoint = "https://api.newrelic.com/graphql"
+	euEndpoint = "https://api.eu.newrelic.com/graphql"
+)
+
+// ConditionType mirrors New Relic's NrqlConditionType enum.
+type ConditionType string
+
+const (
+	ConditionTypeStatic   ConditionType = "STATIC"
+	ConditionTypeBaseline ConditionType = "BASELINE"
+	ConditionTypeOutlier  ConditionType = "OUTLIER"
+)
+
+// Term represents a single critical/warning threshold on an NRQL condition.
+type Term struct {
+	Priority             string  `json:"priority"`
+	Operator             string  `json:"operator"`
+	Threshold            float64 `json:"threshold"`
+	ThresholdDuration    int     `json:"thresholdDuration"`
+	ThresholdOccurrences string  `json:"thresholdOccurrences"`
+}
+
+// Signal configures how a condition evaluates its NRQL query over time.
+type Signal struct {
+	AggregationWindow int `json:"aggregationWindow"`
+	EvaluationOffset  int `json:"evaluationOffset"`
+}
+
+// NRQLCondition is a single NRQL alert condition as returned by NerdGraph.
+// AccountID is not part of the NerdGraph response itself (it is stamped on
+// by the client after a fetch, since the caller already knows which account
+// it queried) but is still tagged for JSON so it round-trips through
+// exported/baseline snapshots.
+type NRQLCondition struct {
+	AccountID int           `json:"accountId"`
+	PolicyID  string        `json:"policyId"`
+	Name      string        `json:"name"`
+	Type      ConditionType `json:"type"`
+	Enabled   bool          `json:"enabled"`
+	Nrql      struct {
+		Query string `json:"query"`
+	} `json:"nrql"`
+	Terms      []Term `json:"terms"`
+	RunbookURL string `json:"runbookUrl"`
+	Signal     Signal `json:"signal"`
+}
+
+// DefaultMaxRetries is the number of times a request is retried after a
+// retryable (5xx or 429) response before FetchNRQLConditions gives up.
+const DefaultMaxRetries = 3
+
+// GraphQLClient issues NerdGraph queries against New Relic's GraphQL API.
+// HTTPClient is exported so tests can inject a fake RoundTripper instead of
+// hitting the real NerdGraph endpoint.
+type GraphQLClient struct {
+	APIKey     string
+	Region     Region
+	HTTPClient *http.Client
+	MaxRetries int
+}
+
+// NewGraphQLClient returns a GraphQLClient for the given API key and region,
+// with an HTTP timeout of timeout and up to DefaultMaxRetries retries on
+// retryable responses.
+func NewGraphQLClient(apiKey string, region Region, timeout time.Duration) *GraphQLClient {
+	return &GraphQLClient{
+		APIKey:     apiKey,
+		Region:     region,
+		HTTPClient: &http.Client{Timeout: timeout},
+		MaxRetries: DefaultMaxRetries,
+	}
+}
+
+func (c *GraphQLClient) endpoint() string {
+	if c.Region == RegionEU {
+		return euEndpoint
+	}
+	return usEndpoint
+}
+
+const nrqlConditionsSearchQuery = `
+query($accountId: Int!, $policyId: ID, $cursor: String) {
+  actor {
+    account(id: $accountId) {
+      alerts {
+        nrqlConditionsSearch(searchCriteria: {policyId: $policyId}, cursor: $cursor) {
+          nrqlConditions {
+            policyId
+            name
+            type
+            enabled
+            runbookUrl
+            nrql {
+              query
+            }
+            terms {
+              priority
+              operator
+              threshold
+              thresholdDuration
+              thresholdOccurrences
+            }
+            signal {
+              aggregationWindow
+              evaluationOffset
+            }
+          }
+          nextCursor
+        }
+      }
+    }
+  }
+}
+`
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data struct {
+		Actor struct {
+			Account struct {
+				Alerts struct {
+					NrqlConditionsSearch struct {
+						NrqlConditions []NRQLCondition `json:"nrqlConditions"`
+						NextCursor     *string         `json:"nextCursor"`
+					} `json:"nrqlConditionsSearch"`
+				} `json:"alerts"`
+			} `json:"account"`
+		} `json:"actor"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// FetchNRQLConditions fetches every NRQL condition for policyID within
+// accountID, following nextCursor until the result set is exhausted. Requests
+// that fail with a 5xx or 429 response are retried with exponential backoff
+// and jitter, honoring the ctx deadline and any Retry-After header.
+func (c *GraphQLClient) FetchNRQLConditions(ctx context.Context, accountID int, policyID string) ([]NRQLCondition, error) {
+	var all []NRQLCondition
+	cursor := ""
+
+	for {
+		variables := map[string]interface{}{
+			"accountId": accountID,
+			"policyId":  policyID,
+		}
+		if cursor != "" {
+			variables["cursor"] = cursor
+		}
+
+		resp, err := c.doWithRetry(ctx, variables)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("nerdgraph returned errors: %s", resp.Errors[0].Message)
+		}
+
+		search := resp.Data.Actor.Account.Alerts.NrqlConditionsSearch
+		for i := range search.NrqlConditions {
+			search.NrqlConditions[i].AccountID = accountID
+		}
+		all = append(all, search.NrqlConditions...)
+
+		if search.NextCursor == nil || *search.NextCursor == "" {
+			break
+		}
+		cursor = *search.NextCursor
+	}
+
+	return all, nil
+}
+
+// doWithRetry calls do, retrying retryable responses (5xx, 429) up to
+// c.MaxRetries times with exponential backoff and jitter.
+func (c *GraphQLClient) doWithRetry(ctx context.Context, variables map[string]interface{}) (*graphQLResponse, error) {
+	maxRetries := c.MaxRetries
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(attempt, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		resp, err := c.do(ctx, variables)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if _, ok := err.(*retryableError); !ok {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("nerdgraph request failed after %d retries: %w", maxRetries, lastErr)
+}
+
+// retryableError marks an error as safe to retry, optionally carrying the
+// server-requested Retry-After delay.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// backoffDelay returns the delay before the given retry attempt: the
+// server's Retry-After if one was provided, otherwise exponential backoff
+// with jitter.
+func backoffDelay(attempt int, lastErr error) time.Duration {
+	if re, ok := lastErr.(*retryableError); ok && re.retryAfter > 0 {
+		return re.retryAfter
+	}
+	base := time.Duration(1<<uint(attempt-1)) * time.Second
+	jitter := time.Duration(rand.Int63n(int64(base) + 1))
+	return base + jitter
+}
+
+func (c *GraphQLClient) do(ctx context.Context, variables map[string]interface{}) (*graphQLResponse, error) {
+	body, err := json.Marshal(graphQLRequest{Query: nrqlConditionsSearchQuery, Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("API-Key", c.APIKey)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		err := fmt.Errorf("nerdgraph request failed with status %d", resp.StatusCode)
+		return nil, &retryableError{err: err, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nerdgraph request failed with status %d", resp.StatusCode)
+	}
+
+	var gqlResp graphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gqlResp); err != nil {
+		return nil, err
+	}
+
+	return &gqlResp, nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds. It returns 0
+// if the header is absent or not a plain integer (e.g. an HTTP-date, which
+// is rare for NerdGraph and not worth the extra parsing complexity here).
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
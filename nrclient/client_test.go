@@ -0,0 +1,165 @@
+package nrclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *GraphQLClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewGraphQLClient("test-api-key", RegionUS, 5*time.Second)
+	client.HTTPClient.Transport = rewriteTransport{baseURL: server.URL}
+	return client
+}
+
+// rewriteTransport redirects requests to a test server regardless of the
+// scheme/host GraphQLClient built them against.
+type rewriteTransport struct {
+	baseURL string
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := t.baseURL + req.URL.Path
+	redirected, err := http.NewRequestWithContext(req.Context(), req.Method, url, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	redirected.Header = req.Header
+	return http.DefaultTransport.RoundTrip(redirected)
+}
+
+func conditionsResponse(conditions []NRQLCondition, nextCursor *string) graphQLResponse {
+	var resp graphQLResponse
+	resp.Data.Actor.Account.Alerts.NrqlConditionsSearch.NrqlConditions = conditions
+	resp.Data.Actor.Account.Alerts.NrqlConditionsSearch.NextCursor = nextCursor
+	return resp
+}
+
+func TestFetchNRQLConditions_Pagination(t *testing.T) {
+	cursor := "page-2"
+	pages := []graphQLResponse{
+		conditionsResponse([]NRQLCondition{{Name: "first"}}, &cursor),
+		conditionsResponse([]NRQLCondition{{Name: "second"}}, nil),
+	}
+
+	var requestCount int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if requestCount >= len(pages) {
+			t.Fatalf("unexpected request %d", requestCount)
+		}
+		page := pages[requestCount]
+		requestCount++
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	conditions, err := client.FetchNRQLConditions(context.Background(), 123, "policy-1")
+	if err != nil {
+		t.Fatalf("FetchNRQLConditions returned error: %v", err)
+	}
+	if len(conditions) != 2 {
+		t.Fatalf("got %d conditions, want 2", len(conditions))
+	}
+	if conditions[0].Name != "first" || conditions[1].Name != "second" {
+		t.Fatalf("got conditions %+v, want [first second] in order", conditions)
+	}
+	if requestCount != 2 {
+		t.Fatalf("got %d requests, want 2 (one per page)", requestCount)
+	}
+	for _, c := range conditions {
+		if c.AccountID != 123 {
+			t.Errorf("condition %q has AccountID %d, want 123", c.Name, c.AccountID)
+		}
+	}
+}
+
+func TestFetchNRQLConditions_RetriesOn5xxThenSucceeds(t *testing.T) {
+	var requestCount int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(conditionsResponse([]NRQLCondition{{Name: "ok"}}, nil))
+	})
+	client.MaxRetries = 5
+
+	conditions, err := client.FetchNRQLConditions(context.Background(), 1, "policy-1")
+	if err != nil {
+		t.Fatalf("FetchNRQLConditions returned error: %v", err)
+	}
+	if len(conditions) != 1 || conditions[0].Name != "ok" {
+		t.Fatalf("got %+v, want a single 'ok' condition", conditions)
+	}
+	if requestCount != 3 {
+		t.Fatalf("got %d requests, want 3 (2 failures + 1 success)", requestCount)
+	}
+}
+
+func TestFetchNRQLConditions_HonorsRetryAfter(t *testing.T) {
+	var requestCount int
+	var firstAttempt, secondAttempt time.Time
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount == 1 {
+			firstAttempt = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		secondAttempt = time.Now()
+		json.NewEncoder(w).Encode(conditionsResponse([]NRQLCondition{{Name: "ok"}}, nil))
+	})
+	client.MaxRetries = 1
+
+	if _, err := client.FetchNRQLConditions(context.Background(), 1, "policy-1"); err != nil {
+		t.Fatalf("FetchNRQLConditions returned error: %v", err)
+	}
+	if requestCount != 2 {
+		t.Fatalf("got %d requests, want 2", requestCount)
+	}
+	if gap := secondAttempt.Sub(firstAttempt); gap < time.Second {
+		t.Fatalf("retry happened after %v, want at least the 1s Retry-After", gap)
+	}
+}
+
+func TestFetchNRQLConditions_MaxRetriesZeroFailsFast(t *testing.T) {
+	var requestCount int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	client.MaxRetries = 0
+
+	if _, err := client.FetchNRQLConditions(context.Background(), 1, "policy-1"); err == nil {
+		t.Fatal("expected an error for a persistent 500 response")
+	}
+	if requestCount != 1 {
+		t.Fatalf("got %d requests with MaxRetries=0, want exactly 1 (no retries)", requestCount)
+	}
+}
+
+func TestFetchNRQLConditions_NonRetryableErrorStopsImmediately(t *testing.T) {
+	var requestCount int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	client.MaxRetries = 5
+
+	if _, err := client.FetchNRQLConditions(context.Background(), 1, "policy-1"); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+	if requestCount != 1 {
+		t.Fatalf("got %d requests for a non-retryable 400, want exactly 1", requestCount)
+	}
+}
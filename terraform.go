@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andikanugr/newrelic-condition-collector/nrclient"
+)
+
+// errWriter wraps an io.Writer and remembers the first error any of its
+// writes produced, so a long sequence of Fprintf/Fprintln calls can be
+// written without checking each one individually and still surface a
+// partial write (e.g. disk full) instead of silently truncating the file.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+func (ew *errWriter) println(args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintln(ew.w, args...)
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sanitizeResourceName turns an NRQL condition name into a valid Terraform
+// resource name: lowercase, non-alphanumeric runs collapsed to a single
+// underscore, and leading/trailing underscores trimmed.
+func sanitizeResourceName(name string) string {
+	sanitized := nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "_")
+	sanitized = strings.Trim(sanitized, "_")
+	if sanitized == "" {
+		sanitized = "condition"
+	}
+	return sanitized
+}
+
+// SaveNRQLConditionsAsTerraform writes one newrelic_nrql_alert_condition
+// resource block per condition to filename, so existing New Relic policies
+// can be round-tripped into Terraform-managed IaC.
+func SaveNRQLConditionsAsTerraform(filename string, conditions []nrclient.NRQLCondition) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ew := &errWriter{w: file}
+
+	used := make(map[string]int)
+	for _, condition := range conditions {
+		resourceName := sanitizeResourceName(condition.Name)
+		if n := used[resourceName]; n > 0 {
+			used[resourceName]++
+			resourceName = fmt.Sprintf("%s_%d", resourceName, n+1)
+		} else {
+			used[resourceName] = 1
+		}
+
+		ew.printf("resource \"newrelic_nrql_alert_condition\" %q {\n", resourceName)
+		ew.printf("  policy_id = %q\n", condition.PolicyID)
+		ew.printf("  name      = %q\n", condition.Name)
+		ew.printf("  type      = %q\n", strings.ToLower(string(condition.Type)))
+		ew.printf("  enabled   = %t\n\n", condition.Enabled)
+
+		ew.println("  nrql {")
+		ew.printf("    query = %q\n", condition.Nrql.Query)
+		ew.println("  }")
+
+		for _, term := range condition.Terms {
+			block := strings.ToLower(term.Priority)
+			ew.printf("\n  %s {\n", block)
+			ew.printf("    operator              = %q\n", strings.ToLower(term.Operator))
+			ew.printf("    threshold             = %s\n", strconv.FormatFloat(term.Threshold, 'f', -1, 64))
+			ew.printf("    threshold_duration    = %d\n", term.ThresholdDuration)
+			ew.printf("    threshold_occurrences = %q\n", term.ThresholdOccurrences)
+			ew.println("  }")
+		}
+
+		if condition.RunbookURL != "" {
+			ew.printf("\n  runbook_url = %q\n", condition.RunbookURL)
+		}
+
+		ew.println("}")
+		ew.println()
+	}
+
+	return ew.err
+}